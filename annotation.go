@@ -1,12 +1,11 @@
 package gapi
 
 import (
-	"bytes"
-	"encoding/json"
-	"errors"
+	"context"
 	"fmt"
-	"io/ioutil"
 	"net/url"
+	"strconv"
+	"time"
 )
 
 // Annotation represents a Grafana API Annotation
@@ -39,33 +38,30 @@ type GraphiteAnnotation struct {
 
 // Annotations fetches the annotations queried with the params it's passed
 func (c *Client) Annotations(params url.Values) ([]Annotation, error) {
-	req, err := c.newRequest("GET", "/api/annotation", params, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := c.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	if resp.StatusCode != 200 {
-		return nil, errors.New(resp.Status)
-	}
-
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
+	return c.AnnotationsContext(context.Background(), params)
+}
 
+// AnnotationsContext fetches the annotations queried with the params it's
+// passed. It hits the plural /api/annotations list endpoint -- the
+// singular /api/annotation used before this request-helper refactor was a
+// pre-existing baseline bug that never reached a real endpoint.
+func (c *Client) AnnotationsContext(ctx context.Context, params url.Values) ([]Annotation, error) {
 	result := []Annotation{}
-	err = json.Unmarshal(data, &result)
+	err := c.request(ctx, "GET", "/api/annotations", params, nil, &result)
 	return result, err
 }
 
 // Annotation fetches the annotation queried with the ID and params it's passed.
-// It returns an error if no annotation with a matching ID is found.
+// It returns ErrNotFound if no annotation with a matching ID is found.
 func (c *Client) Annotation(id int64, params url.Values) (Annotation, error) {
-	as, err := c.Annotations(params)
+	return c.AnnotationContext(context.Background(), id, params)
+}
+
+// AnnotationContext fetches the annotation queried with the ID and params
+// it's passed. It returns ErrNotFound if no annotation with a matching ID
+// is found.
+func (c *Client) AnnotationContext(ctx context.Context, id int64, params url.Values) (Annotation, error) {
+	as, err := c.AnnotationsContext(ctx, params)
 	if err != nil {
 		return Annotation{}, err
 	}
@@ -76,187 +72,171 @@ func (c *Client) Annotation(id int64, params url.Values) (Annotation, error) {
 		}
 	}
 
-	return Annotation{}, fmt.Errorf("annotation %v not found", id)
+	return Annotation{}, ErrNotFound
 }
 
 // NewAnnotation creates a new annotation with the Annotation it is passed
 func (c *Client) NewAnnotation(a *Annotation) (int64, error) {
-	data, err := json.Marshal(a)
-	if err != nil {
-		return 0, err
-	}
-	req, err := c.newRequest("POST", "/api/annotations", nil, bytes.NewBuffer(data))
-	if err != nil {
-		return 0, err
-	}
-
-	resp, err := c.Do(req)
-	if err != nil {
-		return 0, err
-	}
-	if resp.StatusCode != 200 {
-		return 0, errors.New(resp.Status)
-	}
-
-	data, err = ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return 0, err
-	}
+	return c.NewAnnotationContext(context.Background(), a)
+}
 
+// NewAnnotationContext creates a new annotation with the Annotation it is
+// passed.
+func (c *Client) NewAnnotationContext(ctx context.Context, a *Annotation) (int64, error) {
 	result := struct {
 		ID int64 `json:"id"`
 	}{}
-	err = json.Unmarshal(data, &result)
+	err := c.request(ctx, "POST", "/api/annotations", nil, a, &result)
 	return result.ID, err
 }
 
 // NewGraphiteAnnotation creates a new annotation with the GraphiteAnnotation it is passed
 func (c *Client) NewGraphiteAnnotation(gfa *GraphiteAnnotation) (int64, error) {
-	data, err := json.Marshal(gfa)
-	if err != nil {
-		return 0, err
-	}
-	req, err := c.newRequest("POST", "/api/annotations/graphite", nil, bytes.NewBuffer(data))
-	if err != nil {
-		return 0, err
-	}
-
-	resp, err := c.Do(req)
-	if err != nil {
-		return 0, err
-	}
-	if resp.StatusCode != 200 {
-		return 0, errors.New(resp.Status)
-	}
-
-	data, err = ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return 0, err
-	}
+	return c.NewGraphiteAnnotationContext(context.Background(), gfa)
+}
 
+// NewGraphiteAnnotationContext creates a new annotation with the
+// GraphiteAnnotation it is passed.
+func (c *Client) NewGraphiteAnnotationContext(ctx context.Context, gfa *GraphiteAnnotation) (int64, error) {
 	result := struct {
 		ID int64 `json:"id"`
 	}{}
-	err = json.Unmarshal(data, &result)
+	err := c.request(ctx, "POST", "/api/annotations/graphite", nil, gfa, &result)
 	return result.ID, err
 }
 
 // UpdateAnnotation updates all properties an existing annotation with the Annotation it is passed.
 func (c *Client) UpdateAnnotation(id int64, a *Annotation) (string, error) {
-	path := fmt.Sprintf("/api/annotations/%d", id)
-	data, err := json.Marshal(a)
-	if err != nil {
-		return "", err
-	}
-	req, err := c.newRequest("PUT", path, nil, bytes.NewBuffer(data))
-	if err != nil {
-		return "", err
-	}
-
-	resp, err := c.Do(req)
-	if err != nil {
-		return "", err
-	}
-	if resp.StatusCode != 200 {
-		return "", errors.New(resp.Status)
-	}
-
-	data, err = ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
+	return c.UpdateAnnotationContext(context.Background(), id, a)
+}
 
+// UpdateAnnotationContext updates all properties an existing annotation
+// with the Annotation it is passed.
+func (c *Client) UpdateAnnotationContext(ctx context.Context, id int64, a *Annotation) (string, error) {
 	result := struct {
 		Message string `json:"message"`
 	}{}
-	err = json.Unmarshal(data, &result)
+	err := c.request(ctx, "PUT", fmt.Sprintf("/api/annotations/%d", id), nil, a, &result)
 	return result.Message, err
 }
 
 // PatchAnnotation updates one or more properties of an existing annotation that matches the specified ID.
 func (c *Client) PatchAnnotation(id int64, a *Annotation) (string, error) {
-	path := fmt.Sprintf("/api/annotations/%d", id)
-	data, err := json.Marshal(a)
-	if err != nil {
-		return "", err
-	}
-	req, err := c.newRequest("PATCH", path, nil, bytes.NewBuffer(data))
-	if err != nil {
-		return "", err
-	}
-
-	resp, err := c.Do(req)
-	if err != nil {
-		return "", err
-	}
-	if resp.StatusCode != 200 {
-		return "", errors.New(resp.Status)
-	}
-
-	data, err = ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
+	return c.PatchAnnotationContext(context.Background(), id, a)
+}
 
+// PatchAnnotationContext updates one or more properties of an existing
+// annotation that matches the specified ID.
+func (c *Client) PatchAnnotationContext(ctx context.Context, id int64, a *Annotation) (string, error) {
 	result := struct {
 		Message string `json:"message"`
 	}{}
-	err = json.Unmarshal(data, &result)
+	err := c.request(ctx, "PATCH", fmt.Sprintf("/api/annotations/%d", id), nil, a, &result)
 	return result.Message, err
 }
 
 // DeleteAnnotation deletes the annotation of the ID it is passed
 func (c *Client) DeleteAnnotation(id int64) (string, error) {
-	path := fmt.Sprintf("/api/annotations/%d", id)
-	req, err := c.newRequest("DELETE", path, nil, bytes.NewBuffer(nil))
-	if err != nil {
-		return "", err
-	}
-
-	resp, err := c.Do(req)
-	if err != nil {
-		return "", err
-	}
-	if resp.StatusCode != 200 {
-		return "", errors.New(resp.Status)
-	}
-
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
+	return c.DeleteAnnotationContext(context.Background(), id)
+}
 
+// DeleteAnnotationContext deletes the annotation of the ID it is passed
+func (c *Client) DeleteAnnotationContext(ctx context.Context, id int64) (string, error) {
 	result := struct {
 		Message string `json:"message"`
 	}{}
-	err = json.Unmarshal(data, &result)
+	err := c.request(ctx, "DELETE", fmt.Sprintf("/api/annotations/%d", id), nil, nil, &result)
 	return result.Message, err
 }
 
 // DeleteAnnotationByRegionID deletes the annotation corresponding to the region ID it is passed
 func (c *Client) DeleteAnnotationByRegionID(id int64) (string, error) {
-	path := fmt.Sprintf("/api/annotations/region/%d", id)
-	req, err := c.newRequest("DELETE", path, nil, bytes.NewBuffer(nil))
-	if err != nil {
-		return "", err
-	}
-
-	resp, err := c.Do(req)
-	if err != nil {
-		return "", err
-	}
-	if resp.StatusCode != 200 {
-		return "", errors.New(resp.Status)
-	}
-
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
+	return c.DeleteAnnotationByRegionIDContext(context.Background(), id)
+}
 
+// DeleteAnnotationByRegionIDContext deletes the annotation corresponding
+// to the region ID it is passed
+func (c *Client) DeleteAnnotationByRegionIDContext(ctx context.Context, id int64) (string, error) {
 	result := struct {
 		Message string `json:"message"`
 	}{}
-	err = json.Unmarshal(data, &result)
+	err := c.request(ctx, "DELETE", fmt.Sprintf("/api/annotations/region/%d", id), nil, nil, &result)
 	return result.Message, err
 }
+
+// AnnotationQuery builds a query for QueryAnnotations using typed fields,
+// so callers don't need to know Grafana's wire parameter names (e.g.
+// "dashboardId") or that From/To are sent as epoch milliseconds.
+type AnnotationQuery struct {
+	From, To time.Time
+
+	Tags []string
+
+	DashboardID int64
+	PanelID     int64
+	AlertID     int64
+
+	// Type restricts results to "alert" or "annotation". Leave empty to
+	// return both.
+	Type string
+
+	Limit int
+
+	// MatchAny changes tag matching from "all tags must match" to "any
+	// tag may match".
+	MatchAny bool
+}
+
+func (q AnnotationQuery) values() url.Values {
+	values := url.Values{}
+	if !q.From.IsZero() {
+		values.Set("from", strconv.FormatInt(q.From.UnixNano()/int64(time.Millisecond), 10))
+	}
+	if !q.To.IsZero() {
+		values.Set("to", strconv.FormatInt(q.To.UnixNano()/int64(time.Millisecond), 10))
+	}
+	for _, tag := range q.Tags {
+		values.Add("tags", tag)
+	}
+	if q.DashboardID != 0 {
+		values.Set("dashboardId", strconv.FormatInt(q.DashboardID, 10))
+	}
+	if q.PanelID != 0 {
+		values.Set("panelId", strconv.FormatInt(q.PanelID, 10))
+	}
+	if q.AlertID != 0 {
+		values.Set("alertId", strconv.FormatInt(q.AlertID, 10))
+	}
+	if q.Type != "" {
+		values.Set("type", q.Type)
+	}
+	if q.Limit != 0 {
+		values.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.MatchAny {
+		values.Set("matchAny", "true")
+	}
+	return values
+}
+
+// QueryAnnotations fetches the annotations matching q, converting it to
+// Grafana's wire format. For cases q doesn't cover, use AnnotationsContext
+// with a raw url.Values instead.
+func (c *Client) QueryAnnotations(ctx context.Context, q AnnotationQuery) ([]Annotation, error) {
+	return c.AnnotationsContext(ctx, q.values())
+}
+
+// findAnnotationLimit bounds the list FindAnnotation scans. Grafana's list
+// endpoint defaults to the 100 most recent annotations and orders
+// newest-first, which would silently hide older IDs from an unbounded
+// scan; this pushes the limit well past that default instead.
+const findAnnotationLimit = 10000
+
+// FindAnnotation fetches the annotation with the given ID. Grafana's
+// annotation API has no single-annotation GET endpoint, so this still
+// lists and scans like AnnotationContext does, requesting up to
+// findAnnotationLimit annotations (newest first) to search. It returns
+// ErrNotFound if no matching annotation is found within that limit.
+func (c *Client) FindAnnotation(ctx context.Context, id int64) (Annotation, error) {
+	return c.AnnotationContext(ctx, id, AnnotationQuery{Limit: findAnnotationLimit}.values())
+}
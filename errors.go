@@ -0,0 +1,76 @@
+package gapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// ErrNotFound reports a missing resource. Any *APIError carrying a 404
+// status matches it via errors.Is, and Client.Annotation (and its Context
+// variants) return it directly when no listed annotation has the requested
+// ID. Check for it with errors.Is, e.g.:
+//
+//	_, err := client.Annotation(id, nil)
+//	if errors.Is(err, gapi.ErrNotFound) {
+//		...
+//	}
+var ErrNotFound = fmt.Errorf("not found")
+
+// APIError is returned whenever the Grafana API responds with a non-200
+// status code. It preserves the status and raw response body so callers
+// can distinguish, for example, a 404 from a 500 instead of matching on
+// the stringified resp.Status.
+type APIError struct {
+	StatusCode int
+	Status     string
+	Body       []byte
+
+	// Message is the "message" field from the response body, if the
+	// response was JSON and had one.
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("status: %s, message: %s", e.Status, e.Message)
+	}
+	return fmt.Sprintf("status: %s, body: %s", e.Status, e.Body)
+}
+
+// Is allows errors.Is(err, ErrNotFound) to succeed for any *APIError
+// carrying a 404 status code.
+func (e *APIError) Is(target error) bool {
+	if e == nil {
+		return false
+	}
+	if target == ErrNotFound {
+		return e.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+// newAPIError reads resp.Body and builds an *APIError describing the
+// non-200 response. The caller remains responsible for closing resp.Body.
+func newAPIError(resp *http.Response) error {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return apiErr
+	}
+	apiErr.Body = body
+
+	parsed := struct {
+		Message string `json:"message"`
+	}{}
+	if err := json.Unmarshal(body, &parsed); err == nil {
+		apiErr.Message = parsed.Message
+	}
+
+	return apiErr
+}
@@ -1,11 +1,10 @@
 package gapi
 
 import (
-	"bytes"
-	"encoding/json"
-	"errors"
+	"context"
 	"fmt"
-	"io/ioutil"
+	"net/url"
+	"strconv"
 )
 
 const (
@@ -14,6 +13,52 @@ const (
 	OrgUserRoleEditor = "Editor"
 )
 
+// defaultOrgsPerPage is the page size OrgsContext requests when paging
+// through results on the caller's behalf. It matches Grafana's own
+// default "perpage".
+const defaultOrgsPerPage = 1000
+
+// OrgsQuery selects a page of results from Client.OrgsPage.
+type OrgsQuery struct {
+	// Page is 1-indexed; a value <= 0 means "the first page".
+	Page int
+	// PerPage defaults to Grafana's own default (1000) when <= 0.
+	PerPage int
+	// Query, if set, filters orgs by name.
+	Query string
+}
+
+func (q OrgsQuery) values() url.Values {
+	values := url.Values{}
+	if q.Page > 0 {
+		values.Set("page", strconv.Itoa(q.Page))
+	}
+	if q.PerPage > 0 {
+		values.Set("perpage", strconv.Itoa(q.PerPage))
+	}
+	if q.Query != "" {
+		values.Set("query", q.Query)
+	}
+	return values
+}
+
+// OrgUsersQuery selects results from Org.UsersPage. Unlike OrgsQuery, it
+// has no Page/PerPage fields: Grafana's /api/orgs/:orgId/users endpoint
+// always returns the full member list and has no paging parameters to
+// send.
+type OrgUsersQuery struct {
+	// Query, if set, filters users by login, email, or name.
+	Query string
+}
+
+func (q OrgUsersQuery) values() url.Values {
+	values := url.Values{}
+	if q.Query != "" {
+		values.Set("query", q.Query)
+	}
+	return values
+}
+
 type OrgUser struct {
 	User
 	Role  string `json:"role"`
@@ -43,191 +88,179 @@ func (o Org) String() string {
 // DataSources use the given client to return the datasources
 // for the organisation
 func (o Org) DataSources(c *Client) ([]*DataSource, error) {
-	return c.DataSourcesByOrgId(o.Id)
+	return c.WithOrgID(o.Id).DataSourcesByOrgId(o.Id)
 }
 
 // AddUser will add a user to the organisation
 func (o Org) AddUser(c *Client, username, role string) error {
+	return o.AddUserContext(context.Background(), c, username, role)
+}
+
+// AddUserContext will add a user to the organisation
+func (o Org) AddUserContext(ctx context.Context, c *Client, username, role string) error {
 	validRole := role == OrgUserRoleAdmin || role == OrgUserRoleEditor || role == OrgUserRoleViewer
 	if !validRole {
 		return fmt.Errorf("invalid role name: %s", role)
 	}
 
-	data, err := json.Marshal(map[string]string{"role": role, "loginOrEmail": username})
-	if err != nil {
-		return err
-	}
-
-	req, err := c.newRequest("POST", fmt.Sprintf("/api/orgs/%d/users", o.Id), bytes.NewReader(data))
-	if err != nil {
-		return err
-	}
-	resp, err := c.Do(req)
-	if err != nil {
-		return err
-	}
-	if resp.StatusCode != 200 {
-		return errors.New(resp.Status)
-	}
-	_, err = ioutil.ReadAll(resp.Body)
-	return err
+	body := map[string]string{"role": role, "loginOrEmail": username}
+	return c.request(ctx, "POST", fmt.Sprintf("/api/orgs/%d/users", o.Id), nil, body, nil)
 }
 
 // Dashboards use the given client to return the dashboards
 // for the organisation
+//
+// Not yet wired through WithOrgID like DataSources and Users are: it has
+// no request to scope since it's still a stub pending a real
+// implementation.
 func (o Org) Dashboards(c *Client) ([]*Dashboard, error) {
-	return []*Dashboard{}, errors.New("not implemented")
+	return []*Dashboard{}, fmt.Errorf("not implemented")
 }
 
 // Users use the given client to return the users
 // for the organisation
 func (o Org) Users(c *Client) ([]OrgUser, error) {
-	ousers := []OrgUser{}
+	return o.UsersContext(context.Background(), c)
+}
 
-	req, err := c.newRequest("GET", fmt.Sprintf("/api/orgs/%d/users", o.Id), nil)
-	if err != nil {
-		return ousers, err
-	}
-	resp, err := c.Do(req)
-	if err != nil {
-		return ousers, err
-	}
-	if resp.StatusCode != 200 {
-		return ousers, errors.New(resp.Status)
-	}
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return ousers, err
-	}
-	err = json.Unmarshal(data, &ousers)
+// UsersContext use the given client to return the users for the
+// organisation. Unlike OrgsContext, this does not loop: Grafana's
+// /api/orgs/:orgId/users endpoint ignores page/perpage and always returns
+// the full member list, so a single request already has everything.
+func (o Org) UsersContext(ctx context.Context, c *Client) ([]OrgUser, error) {
+	return o.UsersPage(ctx, c, OrgUsersQuery{})
+}
+
+// UsersPage use the given client to return the users for the
+// organisation matching q.
+func (o Org) UsersPage(ctx context.Context, c *Client, q OrgUsersQuery) ([]OrgUser, error) {
+	ousers := []OrgUser{}
+	err := c.WithOrgID(o.Id).request(ctx, "GET", fmt.Sprintf("/api/orgs/%d/users", o.Id), q.values(), nil, &ousers)
 	return ousers, err
 }
 
 // RemoveUser removes the user from the organisation
 func (o Org) RemoveUser(c *Client, userID int64) error {
-	req, err := c.newRequest("DELETE", fmt.Sprintf("/api/orgs/%d/users/%d", o.Id, userID), nil)
-	if err != nil {
-		return err
-	}
-	resp, err := c.Do(req)
-	if err != nil {
-		return err
-	}
-	if resp.StatusCode != 200 {
-		return errors.New(resp.Status)
-	}
-	_, err = ioutil.ReadAll(resp.Body)
-	return err
+	return o.RemoveUserContext(context.Background(), c, userID)
+}
+
+// RemoveUserContext removes the user from the organisation
+func (o Org) RemoveUserContext(ctx context.Context, c *Client, userID int64) error {
+	return c.request(ctx, "DELETE", fmt.Sprintf("/api/orgs/%d/users/%d", o.Id, userID), nil, nil, nil)
 }
 
 // Org returns the organisation with the given ID
 func (c *Client) Org(id int64) (Org, error) {
-	org := Org{}
+	return c.OrgContext(context.Background(), id)
+}
 
-	req, err := c.newRequest("GET", fmt.Sprintf("/api/orgs/%d", id), nil)
-	if err != nil {
-		return org, err
-	}
-	resp, err := c.Do(req)
-	if err != nil {
-		return org, err
-	}
-	if resp.StatusCode != 200 {
-		return org, errors.New(resp.Status)
-	}
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return org, err
-	}
-	err = json.Unmarshal(data, &org)
+// OrgContext returns the organisation with the given ID
+func (c *Client) OrgContext(ctx context.Context, id int64) (Org, error) {
+	org := Org{}
+	err := c.request(ctx, "GET", fmt.Sprintf("/api/orgs/%d", id), nil, nil, &org)
 	return org, err
 }
 
 // OrgByName returns the organisation with the given name
 func (c *Client) OrgByName(name string) (Org, error) {
-	org := Org{}
+	return c.OrgByNameContext(context.Background(), name)
+}
 
-	req, err := c.newRequest("GET", fmt.Sprintf("/api/orgs/name/%s", name), nil)
-	if err != nil {
-		return org, err
-	}
-	resp, err := c.Do(req)
-	if err != nil {
-		return org, err
-	}
-	if resp.StatusCode != 200 {
-		return org, errors.New(resp.Status)
-	}
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return org, err
-	}
-	err = json.Unmarshal(data, &org)
+// OrgByNameContext returns the organisation with the given name
+func (c *Client) OrgByNameContext(ctx context.Context, name string) (Org, error) {
+	org := Org{}
+	err := c.request(ctx, "GET", fmt.Sprintf("/api/orgs/name/%s", name), nil, nil, &org)
 	return org, err
 }
 
 // Orgs returns all the orgs in Grafana
 func (c *Client) Orgs() ([]Org, error) {
-	orgs := make([]Org, 0)
+	return c.OrgsContext(context.Background())
+}
 
-	req, err := c.newRequest("GET", "/api/orgs/", nil)
-	if err != nil {
-		return orgs, err
+// OrgsContext returns all the orgs in Grafana. It transparently pages
+// through the full result set, so the caller gets every org regardless of
+// how many Grafana returns per page.
+func (c *Client) OrgsContext(ctx context.Context) ([]Org, error) {
+	all := make([]Org, 0)
+	q := OrgsQuery{Page: 1, PerPage: defaultOrgsPerPage}
+	for {
+		page, err := c.OrgsPage(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) < q.PerPage {
+			return all, nil
+		}
+		q.Page++
 	}
-	resp, err := c.Do(req)
-	if err != nil {
-		return orgs, err
-	}
-	if resp.StatusCode != 200 {
-		return orgs, errors.New(resp.Status)
-	}
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return orgs, err
-	}
-	err = json.Unmarshal(data, &orgs)
+}
+
+// OrgsPage returns a single page of the orgs in Grafana, as selected by q.
+func (c *Client) OrgsPage(ctx context.Context, q OrgsQuery) ([]Org, error) {
+	orgs := make([]Org, 0)
+	err := c.request(ctx, "GET", "/api/orgs/", q.values(), nil, &orgs)
 	return orgs, err
 }
 
+// OrgsIter returns an iterator over every org in Grafana, paging through
+// results lazily rather than buffering them all up front. It is meant to
+// be used with Go's range-over-func support:
+//
+//	for org, err := range client.OrgsIter(ctx) {
+//		if err != nil {
+//			...
+//		}
+//	}
+func (c *Client) OrgsIter(ctx context.Context) func(yield func(Org, error) bool) {
+	return func(yield func(Org, error) bool) {
+		q := OrgsQuery{Page: 1, PerPage: defaultOrgsPerPage}
+		for {
+			page, err := c.OrgsPage(ctx, q)
+			if err != nil {
+				yield(Org{}, err)
+				return
+			}
+			for _, o := range page {
+				if !yield(o, nil) {
+					return
+				}
+			}
+			if len(page) < q.PerPage {
+				return
+			}
+			q.Page++
+		}
+	}
+}
+
 // NewOrg creates an Org with the given name in Grafana
 func (c *Client) NewOrg(name string) (Org, error) {
+	return c.NewOrgContext(context.Background(), name)
+}
+
+// NewOrgContext creates an Org with the given name in Grafana
+func (c *Client) NewOrgContext(ctx context.Context, name string) (Org, error) {
 	org := Org{Name: name}
-	data, err := json.Marshal(org)
-	req, err := c.newRequest("POST", "/api/orgs", bytes.NewBuffer(data))
-	if err != nil {
-		return org, err
-	}
-	resp, err := c.Do(req)
-	if err != nil {
-		return org, err
-	}
-	if resp.StatusCode != 200 {
-		return org, errors.New(resp.Status)
-	}
 
 	body := struct {
 		ID int64 `json:"orgId"`
-	}{0}
-
-	data, err = ioutil.ReadAll(resp.Body)
-	json.Unmarshal(data, &body)
+	}{}
+	if err := c.request(ctx, "POST", "/api/orgs", nil, org, &body); err != nil {
+		return org, err
+	}
 	org.Id = body.ID
 
-	return org, err
+	return org, nil
 }
 
 // DeleteOrg deletes the given org ID from Grafana
 func (c *Client) DeleteOrg(id int64) error {
-	req, err := c.newRequest("DELETE", fmt.Sprintf("/api/orgs/%d", id), nil)
-	if err != nil {
-		return err
-	}
-	resp, err := c.Do(req)
-	if err != nil {
-		return err
-	}
-	if resp.StatusCode != 200 {
-		return errors.New(resp.Status)
-	}
-	return err
+	return c.DeleteOrgContext(context.Background(), id)
+}
+
+// DeleteOrgContext deletes the given org ID from Grafana
+func (c *Client) DeleteOrgContext(ctx context.Context, id int64) error {
+	return c.request(ctx, "DELETE", fmt.Sprintf("/api/orgs/%d", id), nil, nil, nil)
 }